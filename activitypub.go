@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jadedragon942/shalerocksbot-go/throttle"
+)
+
+/*********************************************************************
+ * 10) ActivityPub Federation
+ *
+ * An optional embedded HTTP server that turns each IRC nick with a
+ * badge or a hunt milestone into a minimal ActivityPub actor, so
+ * fediverse accounts can follow "<nick>@<domain>" and see a Note each
+ * time that nick earns a badge or hits a befriend/shoot milestone.
+ * This mirrors the signed-POST delivery pattern used by honk.
+ *********************************************************************/
+
+const huntMilestoneEvery = 10
+
+// activityPubAddrFlag and activityPubDomainFlag are set from flags in
+// main(). An empty addr disables the feature entirely.
+var (
+	activityPubAddrFlag   string
+	activityPubDomainFlag string
+)
+
+func activityPubEnabled() bool {
+	return activityPubAddrFlag != "" && activityPubDomainFlag != ""
+}
+
+// maybeStartActivityPub starts the embedded ActivityPub server in the
+// background if -activitypub-addr and -activitypub-domain were given. It
+// is a no-op otherwise, the same pattern maybeLoadPlugins uses for an
+// optional feature that shouldn't be fatal to misconfigure.
+func maybeStartActivityPub() {
+	if !activityPubEnabled() {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/webfinger", handleWebfinger)
+	mux.HandleFunc("/users/", handleActorOrInboxOrOutbox)
+
+	log.Printf("[DEBUG] Starting ActivityPub server on %s for domain %s", activityPubAddrFlag, activityPubDomainFlag)
+	go func() {
+		if err := http.ListenAndServe(activityPubAddrFlag, mux); err != nil {
+			log.Printf("[ERROR] ActivityPub server stopped: %v", err)
+		}
+	}()
+}
+
+func actorID(key string) string {
+	return fmt.Sprintf("https://%s/users/%s", activityPubDomainFlag, key)
+}
+
+// actorKey builds the opaque per-identity key actor_keys and followers are
+// stored under, from network/channel/nick rather than the bare nick: the
+// same nick can exist on two different networks (or in two channels on
+// one network) as unrelated people, and sharing a single fediverse actor
+// between them would leak one's badges/hunt milestones into the other's
+// followers. It's stored in those tables' "nick" column, which predates
+// multi-network support and is really "identity key" now.
+func actorKey(network, channel, nick string) string {
+	return strings.Join([]string{
+		url.PathEscape(network),
+		url.PathEscape(channel),
+		url.PathEscape(nick),
+	}, ".")
+}
+
+/*********************************************************************
+ * Actor keys
+ *********************************************************************/
+
+// getOrCreateActorKey returns the RSA keypair for the actor identified by
+// key (see actorKey), generating and persisting a fresh 2048-bit key the
+// first time key is seen.
+func getOrCreateActorKey(key string) (*rsa.PrivateKey, error) {
+	row := db.QueryRow(`SELECT private_key_pem FROM actor_keys WHERE nick = ?`, key)
+	var privPEM string
+	switch err := row.Scan(&privPEM); err {
+	case nil:
+		return parseRSAPrivateKeyPEM(privPEM)
+	case sql.ErrNoRows:
+		// fall through to generation below
+	default:
+		return nil, fmt.Errorf("looking up actor key for %s: %w", key, err)
+	}
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating actor key for %s: %w", key, err)
+	}
+
+	privPEMBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(priv),
+	})
+	pubBytes, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling public key for %s: %w", key, err)
+	}
+	pubPEMBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	if _, err := db.Exec(`
+		INSERT INTO actor_keys (nick, private_key_pem, public_key_pem) VALUES (?, ?, ?)
+	`, key, string(privPEMBytes), string(pubPEMBytes)); err != nil {
+		return nil, fmt.Errorf("storing actor key for %s: %w", key, err)
+	}
+	return priv, nil
+}
+
+func parseRSAPrivateKeyPEM(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in stored private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func publicKeyPEMFor(key string) (string, error) {
+	row := db.QueryRow(`SELECT public_key_pem FROM actor_keys WHERE nick = ?`, key)
+	var pubPEM string
+	if err := row.Scan(&pubPEM); err != nil {
+		return "", err
+	}
+	return pubPEM, nil
+}
+
+/*********************************************************************
+ * HTTP handlers
+ *********************************************************************/
+
+func handleWebfinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	prefix := "acct:"
+	if !strings.HasPrefix(resource, prefix) {
+		http.Error(w, "unsupported resource", http.StatusBadRequest)
+		return
+	}
+	acct := strings.TrimPrefix(resource, prefix)
+	key, domain, ok := strings.Cut(acct, "@")
+	if !ok || domain != activityPubDomainFlag {
+		http.NotFound(w, r)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"subject": resource,
+		"links": []map[string]string{
+			{
+				"rel":  "self",
+				"type": "application/activity+json",
+				"href": actorID(key),
+			},
+		},
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleActorOrInboxOrOutbox routes everything under /users/<key>, where
+// key is the network/channel/nick identifier actorKey builds, since Go's
+// http.ServeMux doesn't do path parameters.
+func handleActorOrInboxOrOutbox(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/users/")
+	switch {
+	case strings.HasSuffix(path, "/inbox"):
+		handleInbox(w, r, strings.TrimSuffix(path, "/inbox"))
+	case strings.HasSuffix(path, "/outbox"):
+		handleOutbox(w, r, strings.TrimSuffix(path, "/outbox"))
+	default:
+		handleActor(w, r, path)
+	}
+}
+
+func handleActor(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := getOrCreateActorKey(key); err != nil {
+		log.Printf("[ERROR] ActivityPub actor key for %s: %v", key, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	pubPEM, err := publicKeyPEMFor(key)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	id := actorID(key)
+	actor := map[string]interface{}{
+		"@context":          []string{"https://www.w3.org/ns/activitystreams"},
+		"id":                id,
+		"type":              "Person",
+		"preferredUsername": key,
+		"name":              key,
+		"inbox":             id + "/inbox",
+		"outbox":            id + "/outbox",
+		"publicKey": map[string]string{
+			"id":           id + "#main-key",
+			"owner":        id,
+			"publicKeyPem": pubPEM,
+		},
+	}
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(actor)
+}
+
+func handleOutbox(w http.ResponseWriter, r *http.Request, key string) {
+	id := actorID(key)
+	collection := map[string]interface{}{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           id + "/outbox",
+		"type":         "OrderedCollection",
+		"totalItems":   0,
+		"orderedItems": []interface{}{},
+	}
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(collection)
+}
+
+// handleInbox accepts Follow activities and persists the follower so
+// PublishNote can deliver to it later. Everything else is acknowledged
+// and ignored; this bot doesn't do anything with Like/Undo/etc. Every
+// Follow must carry a valid HTTP Signature (the same HTTP Signatures
+// draft deliverActivity signs with) whose keyId names the same actor the activity
+// claims to be from - otherwise anyone could POST an unsigned Follow
+// naming someone else's actor and get persisted as a follower.
+func handleInbox(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var activity struct {
+		Type   string `json:"type"`
+		Actor  string `json:"actor"`
+		Object string `json:"object"`
+	}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if activity.Type != "Follow" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := verifyInboundSignature(r, body, activity.Actor); err != nil {
+		log.Printf("[ERROR] ActivityPub: rejecting Follow from %s: %v", activity.Actor, err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	inbox, err := fetchRemoteInbox(activity.Actor)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: resolving inbox for follower %s: %v", activity.Actor, err)
+		http.Error(w, "could not resolve actor", http.StatusBadGateway)
+		return
+	}
+
+	if _, err := db.Exec(`
+		INSERT OR IGNORE INTO followers (nick, actor, inbox) VALUES (?, ?, ?)
+	`, key, activity.Actor, inbox); err != nil {
+		log.Printf("[ERROR] ActivityPub: storing follower %s for %s: %v", activity.Actor, key, err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	go deliverAccept(key, activity.Actor, inbox, activity.Object)
+}
+
+// verifyInboundSignature validates the HTTP Signature on an inbound
+// ActivityPub POST per the same HTTP Signatures draft signRequest signs
+// outgoing requests with: it fetches the signer's actor document for its
+// publicKeyPem and checks that key actually produced this signature, and
+// that the signing actor is the same one the activity claims to be from.
+func verifyInboundSignature(r *http.Request, body []byte, claimedActor string) error {
+	sigHeader := r.Header.Get("Signature")
+	if sigHeader == "" {
+		return fmt.Errorf("missing Signature header")
+	}
+	fields, err := parseSignatureHeader(sigHeader)
+	if err != nil {
+		return fmt.Errorf("parsing Signature header: %w", err)
+	}
+
+	keyID := fields["keyId"]
+	signerActor, _, _ := strings.Cut(keyID, "#")
+	if signerActor == "" || signerActor != claimedActor {
+		return fmt.Errorf("keyId %q does not belong to claimed actor %q", keyID, claimedActor)
+	}
+
+	if digestHeader := r.Header.Get("Digest"); digestHeader != "" {
+		sum := sha256.Sum256(body)
+		want := "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+		if digestHeader != want {
+			return fmt.Errorf("digest header does not match body")
+		}
+	}
+
+	signingString := buildSigningString(r, strings.Fields(fields["headers"]))
+
+	sig, err := base64.StdEncoding.DecodeString(fields["signature"])
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	pubKey, err := fetchActorPublicKey(signerActor)
+	if err != nil {
+		return fmt.Errorf("fetching signer public key: %w", err)
+	}
+
+	hashed := sha256.Sum256([]byte(signingString))
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, hashed[:], sig); err != nil {
+		return fmt.Errorf("signature does not verify: %w", err)
+	}
+	return nil
+}
+
+// parseSignatureHeader splits an HTTP Signatures header of the form
+// `keyId="...",algorithm="...",headers="...",signature="..."` into its
+// named fields.
+func parseSignatureHeader(header string) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		fields[k] = strings.Trim(v, `"`)
+	}
+	if fields["keyId"] == "" || fields["signature"] == "" {
+		return nil, fmt.Errorf("missing keyId or signature field")
+	}
+	return fields, nil
+}
+
+// buildSigningString reconstructs the signed string from r for the given
+// header names, mirroring signRequest's construction on the sending side.
+// An empty headerNames defaults to "date" per the HTTP Signatures draft.
+func buildSigningString(r *http.Request, headerNames []string) string {
+	if len(headerNames) == 0 {
+		headerNames = []string{"date"}
+	}
+	lines := make([]string, 0, len(headerNames))
+	for _, h := range headerNames {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = r.Host
+		default:
+			value = r.Header.Get(h)
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fetchActorPublicKey fetches actorURL's actor document and parses its
+// publicKeyPem.
+func fetchActorPublicKey(actorURL string) (*rsa.PublicKey, error) {
+	if _, err := url.ParseRequestURI(actorURL); err != nil {
+		return nil, fmt.Errorf("invalid actor URL %q: %w", actorURL, err)
+	}
+	req, err := http.NewRequest(http.MethodGet, actorURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/activity+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var actor struct {
+		PublicKey struct {
+			PublicKeyPem string `json:"publicKeyPem"`
+		} `json:"publicKey"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&actor); err != nil {
+		return nil, err
+	}
+	if actor.PublicKey.PublicKeyPem == "" {
+		return nil, fmt.Errorf("actor document for %s has no publicKeyPem", actorURL)
+	}
+
+	block, _ := pem.Decode([]byte(actor.PublicKey.PublicKeyPem))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in actor public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing actor public key: %w", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("actor public key is not an RSA key")
+	}
+	return rsaPub, nil
+}
+
+// fetchRemoteInbox fetches a remote actor document and returns its inbox
+// URL, the one piece of the Follow activity the AP spec doesn't hand us
+// directly.
+func fetchRemoteInbox(actorURL string) (string, error) {
+	if _, err := url.ParseRequestURI(actorURL); err != nil {
+		return "", fmt.Errorf("invalid actor URL %q: %w", actorURL, err)
+	}
+	req, err := http.NewRequest(http.MethodGet, actorURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/activity+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var actor struct {
+		Inbox string `json:"inbox"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&actor); err != nil {
+		return "", err
+	}
+	if actor.Inbox == "" {
+		return "", fmt.Errorf("actor document for %s has no inbox", actorURL)
+	}
+	return actor.Inbox, nil
+}
+
+/*********************************************************************
+ * Outgoing signed delivery
+ *********************************************************************/
+
+// deliveryDedup guards against delivering the same note to the same
+// inbox twice, e.g. when several followers share one inbox. Its key is
+// the note's ID plus the inbox, and every PublishNote call mints a new
+// note ID, so a second call for the same milestone is not deduplicated. It
+// shares its bloom filter implementation with throttle.Filter, but with
+// an empty path and Save never called - this only needs to survive the
+// process's lifetime, not a restart.
+var deliveryDedup = throttle.NewFilter("", 10000, 0.01)
+
+// PublishNote announces summary as a Create{Note} from the
+// network/channel/nick actor to everyone following it. It's
+// fire-and-forget: delivery failures are logged, not returned, since the
+// IRC-facing command has already succeeded by the time this runs.
+func PublishNote(network, channel, nick, summary string) {
+	if !activityPubEnabled() {
+		return
+	}
+	key := actorKey(network, channel, nick)
+
+	rows, err := db.Query(`SELECT actor, inbox FROM followers WHERE nick = ?`, key)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: loading followers for %s: %v", key, err)
+		return
+	}
+	defer rows.Close()
+
+	id := actorID(key)
+	noteID := fmt.Sprintf("%s/notes/%d", id, time.Now().UnixNano())
+	note := map[string]interface{}{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           noteID,
+		"type":         "Create",
+		"actor":        id,
+		"published":    time.Now().UTC().Format(time.RFC3339),
+		"to":           []string{"https://www.w3.org/ns/activitystreams#Public"},
+		"cc":           []string{id + "/followers"},
+		"object": map[string]interface{}{
+			"id":           noteID + "/object",
+			"type":         "Note",
+			"attributedTo": id,
+			"content":      summary,
+		},
+	}
+	body, err := json.Marshal(note)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: marshalling note for %s: %v", key, err)
+		return
+	}
+
+	for rows.Next() {
+		var actor, inbox string
+		if err := rows.Scan(&actor, &inbox); err != nil {
+			continue
+		}
+		dedupKey := noteID + "|" + inbox
+		if deliveryDedup.Test(dedupKey) {
+			continue
+		}
+		deliveryDedup.Add(dedupKey)
+		go deliverActivity(key, inbox, body)
+	}
+}
+
+// deliverAccept sends an Accept{Follow} back to a new follower's inbox,
+// which most fediverse software expects before it'll show the follow as
+// confirmed.
+func deliverAccept(key, followerActor, inbox, followObject string) {
+	id := actorID(key)
+	accept := map[string]interface{}{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id":       fmt.Sprintf("%s/accepts/%d", id, time.Now().UnixNano()),
+		"type":     "Accept",
+		"actor":    id,
+		"object": map[string]interface{}{
+			"type":   "Follow",
+			"actor":  followerActor,
+			"object": followObject,
+		},
+	}
+	body, err := json.Marshal(accept)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: marshalling Accept for %s: %v", key, err)
+		return
+	}
+	deliverActivity(key, inbox, body)
+}
+
+// deliverActivity POSTs body to inbox, signed as key's actor per the
+// HTTP Signatures draft ActivityPub relies on: a Signature header over
+// (request-target), host, date, and digest.
+func deliverActivity(key, inbox string, body []byte) {
+	priv, err := getOrCreateActorKey(key)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: loading key for %s: %v", key, err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: building request to %s: %v", inbox, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/activity+json")
+
+	if err := signRequest(req, actorID(key)+"#main-key", priv, body); err != nil {
+		log.Printf("[ERROR] ActivityPub: signing request to %s: %v", inbox, err)
+		return
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] ActivityPub: delivering to %s: %v", inbox, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[ERROR] ActivityPub: %s rejected delivery with status %d", inbox, resp.StatusCode)
+	}
+}
+
+// signRequest adds Host, Date, Digest, and Signature headers to req per
+// the HTTP Signatures spec, signing over "(request-target) host date
+// digest" with keyID and RSA-SHA256.
+func signRequest(req *http.Request, keyID string, priv *rsa.PrivateKey, body []byte) error {
+	digest := sha256.Sum256(body)
+	req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Host", req.URL.Host)
+
+	signingString := strings.Join([]string{
+		fmt.Sprintf("(request-target): post %s", req.URL.Path),
+		fmt.Sprintf("host: %s", req.URL.Host),
+		fmt.Sprintf("date: %s", req.Header.Get("Date")),
+		fmt.Sprintf("digest: %s", req.Header.Get("Digest")),
+	}, "\n")
+
+	hashed := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed[:])
+	if err != nil {
+		return fmt.Errorf("signing request: %w", err)
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="(request-target) host date digest",signature="%s"`,
+		keyID, base64.StdEncoding.EncodeToString(sig),
+	))
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jadedragon942/shalerocksbot-go/throttle"
+)
+
+/*********************************************************************
+ * Per-Channel Command Policy
+ *
+ * ChannelConfig.Commands and CooldownSeconds are parsed from the YAML
+ * config but, until now, never consulted - every command ran in every
+ * channel regardless. handlePrivmsg checks both before handing a message
+ * to the registry.
+ *********************************************************************/
+
+// commandWord extracts the lower-cased ';command' word Dispatch would act
+// on, or ok=false if msg isn't a command at all. The channel-policy gate
+// in network.go uses the same parsing so it agrees with Dispatch about
+// what word a cooldown or enable/disable entry applies to.
+func commandWord(msg string) (string, bool) {
+	if !strings.HasPrefix(msg, ";") {
+		return "", false
+	}
+	fields := strings.Fields(msg)
+	if len(fields) == 0 {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimPrefix(fields[0], ";")), true
+}
+
+// channelConfig finds cfg's entry for chanName, or nil if the channel
+// isn't one this network is configured to join.
+func (cfg NetworkConfig) channelConfig(chanName string) *ChannelConfig {
+	for i := range cfg.Channels {
+		if strings.EqualFold(cfg.Channels[i].Name, chanName) {
+			return &cfg.Channels[i]
+		}
+	}
+	return nil
+}
+
+// commandEnabled reports whether word may run in ch. A command absent
+// from ch.Commands uses its default (enabled), matching ChannelConfig's
+// doc comment.
+func commandEnabled(ch *ChannelConfig, word string) bool {
+	if ch == nil || ch.Commands == nil {
+		return true
+	}
+	allowed, explicit := ch.Commands[word]
+	return !explicit || allowed
+}
+
+// cooldowns hands out one throttle.Keyed per network/channel that sets a
+// CooldownSeconds, built lazily since the rate is only known once the
+// network's config has loaded. Capacity 1 with a refill rate of
+// 1/CooldownSeconds means a nick gets one command immediately and then
+// has to wait out the cooldown before its next one, in any channel-wide
+// command.
+var (
+	cooldownsMu sync.Mutex
+	cooldowns   = map[string]*throttle.Keyed{}
+)
+
+// allowByCooldown reports whether nick may run a command in ch right
+// now, consuming its cooldown token if so. A channel with no
+// CooldownSeconds set is never rate-limited here.
+func allowByCooldown(network string, ch *ChannelConfig, nick string) bool {
+	if ch == nil || ch.CooldownSeconds <= 0 {
+		return true
+	}
+
+	key := strings.ToLower(network) + "|" + strings.ToLower(ch.Name)
+
+	cooldownsMu.Lock()
+	kb, ok := cooldowns[key]
+	if !ok {
+		kb = throttle.NewKeyed(1, 1/float64(ch.CooldownSeconds))
+		cooldowns[key] = kb
+	}
+	cooldownsMu.Unlock()
+
+	return kb.Allow(strings.ToLower(nick))
+}
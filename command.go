@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+// weatherRequestTimeout bounds how long a single ;weather lookup (which
+// may chain a geocoding call into a weather call) is allowed to take
+// before we give up and tell the channel.
+const weatherRequestTimeout = 15 * time.Second
+
+// weatherRequests tracks in-flight ;weather lookups per network/nick, so
+// a nick firing off a second ;weather cancels their first one instead of
+// both racing to reply.
+var weatherRequests = newRequestCanceler()
+
+/*********************************************************************
+ * 7) Command Registry
+ *********************************************************************/
+
+// CommandRegistry maps command words (and their aliases) to the Command
+// that handles them. Built-in commands are registered at startup in
+// main(); plugins loaded via -plugins register into the same instance.
+// A single registry is shared by every configured Network.
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	byWord   map[string]pluginapi.Command
+	commands []pluginapi.Command // registration order, for ;help
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		byWord: make(map[string]pluginapi.Command),
+	}
+}
+
+// Register adds cmd under its name and all of its aliases, lower-cased.
+// A later registration of the same word wins, so plugins can override a
+// built-in command if they want to.
+func (r *CommandRegistry) Register(cmd pluginapi.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	words := append([]string{cmd.Name()}, cmd.Aliases()...)
+	for _, w := range words {
+		r.byWord[strings.ToLower(w)] = cmd
+	}
+	r.commands = append(r.commands, cmd)
+}
+
+// Lookup finds the Command registered for word (case-insensitive).
+func (r *CommandRegistry) Lookup(word string) (pluginapi.Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmd, ok := r.byWord[strings.ToLower(word)]
+	return cmd, ok
+}
+
+// All returns every registered command in registration order, for ;help.
+func (r *CommandRegistry) All() []pluginapi.Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]pluginapi.Command, len(r.commands))
+	copy(out, r.commands)
+	return out
+}
+
+// Dispatch splits an incoming PRIVMSG on whitespace and, if the first word
+// matches a registered command, runs it against ctx. Anything else (plain
+// chat, or a leading ';' word nothing is registered for) is ignored.
+func (r *CommandRegistry) Dispatch(ctx *pluginapi.Context, msg string) {
+	word, ok := commandWord(msg)
+	if !ok {
+		return
+	}
+
+	cmd, ok := r.Lookup(word)
+	if !ok {
+		return
+	}
+	ctx.Raw = msg
+	if err := cmd.Handle(ctx, strings.Fields(msg)[1:]); err != nil {
+		log.Printf("[ERROR] command %q on %s/%s: %v", cmd.Name(), ctx.Network, ctx.Channel, err)
+	}
+}
+
+/*********************************************************************
+ * 8) Built-in Commands
+ *********************************************************************/
+
+type weatherCommand struct{}
+
+func (weatherCommand) Name() string      { return "weather" }
+func (weatherCommand) Aliases() []string { return nil }
+func (weatherCommand) Help() string      { return "weather <location> - look up the current weather" }
+
+func (weatherCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	if len(args) == 0 {
+		ctx.Reply("Usage: ;weather <location>")
+		return nil
+	}
+	location := strings.TrimSpace(strings.Join(args, " "))
+	if location == "" {
+		ctx.Reply("Usage: ;weather <location>")
+		return nil
+	}
+	go func() {
+		requestKey := ctx.Network + "|" + ctx.Nick
+		reqCtx, cancel := weatherRequests.Start(context.Background(), requestKey, weatherRequestTimeout)
+		defer cancel()
+
+		var summary string
+		var err error
+		if os.Getenv("OWM_V25") != "" {
+			summary, err = fetchWeatherSummary25(reqCtx, location)
+		} else {
+			summary, err = fetchWeatherSummary3(reqCtx, location)
+		}
+		if err != nil {
+			ctx.Reply(fmt.Sprintf("Could not get weather for '%s': %v", location, err))
+			return
+		}
+		ctx.Reply(summary)
+	}()
+	return nil
+}
+
+type askCommand struct{}
+
+func (askCommand) Name() string      { return "ask" }
+func (askCommand) Aliases() []string { return nil }
+func (askCommand) Help() string      { return "ask <a> or <b> [or <c> ...] - pick one for you" }
+
+func (askCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	raw := strings.TrimSpace(strings.Join(args, " "))
+	if !strings.Contains(raw, " or ") {
+		ctx.Reply("perhaps")
+		return nil
+	}
+	options := strings.Split(raw, " or ")
+	var cleaned []string
+	for _, opt := range options {
+		opt = strings.TrimSpace(opt)
+		if opt != "" {
+			cleaned = append(cleaned, opt)
+		}
+	}
+	if len(cleaned) == 0 {
+		ctx.Reply("perhaps")
+		return nil
+	}
+	ctx.Reply(cleaned[rand.Intn(len(cleaned))])
+	return nil
+}
+
+// huntCommand implements ;bef, ;bang, and ;huntscore. They share the same
+// animalhunt bookkeeping, so it is simplest to keep them as one Command
+// that switches on the word it was invoked as.
+type huntCommand struct {
+	word string // "bef", "bang", or "huntscore"
+}
+
+func (h huntCommand) Name() string      { return h.word }
+func (huntCommand) Aliases() []string   { return nil }
+func (h huntCommand) Help() string {
+	switch h.word {
+	case "bef":
+		return "bef - befriend the currently-spawned animal"
+	case "bang":
+		return "bang - shoot the currently-spawned animal"
+	default:
+		return "huntscore - show your befriend/shoot totals"
+	}
+}
+
+func (h huntCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	if h.word == "huntscore" {
+		befCount, shotCount, err := getHuntStats(ctx.Network, ctx.Channel, ctx.Nick)
+		if err != nil {
+			return fmt.Errorf("fetching hunt score: %w", err)
+		}
+		ctx.Reply(fmt.Sprintf("%s's hunt stats: befriended %d, shot %d.", ctx.Nick, befCount, shotCount))
+		return nil
+	}
+
+	if !allowHuntClaim(ctx.Nick) {
+		ctx.Reply(fmt.Sprintf("%s: slow down a bit!", ctx.Nick))
+		return nil
+	}
+
+	net := networkByName(ctx.Network)
+	if net == nil {
+		return fmt.Errorf("unknown network %q", ctx.Network)
+	}
+
+	net.animalMu.Lock()
+	defer net.animalMu.Unlock()
+
+	if !net.activeAnimal.spawned || net.activeAnimal.claimed {
+		ctx.Reply("There was no animal, sowwy!")
+		return nil
+	}
+	net.activeAnimal.claimed = true
+
+	theAnimal := net.activeAnimal.animal
+	action := "shoot"
+	if h.word == "bef" {
+		action = "befriend"
+	}
+	if err := recordAnimalHunt(ctx.Network, ctx.Channel, ctx.Nick, theAnimal, action); err != nil {
+		ctx.Reply(fmt.Sprintf("Database error: %v", err))
+		return err
+	}
+
+	befCount, shotCount, _ := getHuntStats(ctx.Network, ctx.Channel, ctx.Nick)
+	if action == "befriend" {
+		ctx.Reply(fmt.Sprintf("%s befriended the %s! You have now befriended %d and shot %d.",
+			ctx.Nick, theAnimal, befCount, shotCount))
+		if befCount%huntMilestoneEvery == 0 {
+			PublishNote(ctx.Network, ctx.Channel, ctx.Nick, fmt.Sprintf("%s just befriended their %dth animal.", ctx.Nick, befCount))
+		}
+	} else {
+		ctx.Reply(fmt.Sprintf("%s shot the %s! You have now shot %d and befriended %d.",
+			ctx.Nick, theAnimal, shotCount, befCount))
+		if shotCount%huntMilestoneEvery == 0 {
+			PublishNote(ctx.Network, ctx.Channel, ctx.Nick, fmt.Sprintf("%s just shot their %dth animal.", ctx.Nick, shotCount))
+		}
+	}
+	return nil
+}
+
+type tellCommand struct{}
+
+func (tellCommand) Name() string      { return "tell" }
+func (tellCommand) Aliases() []string { return nil }
+func (tellCommand) Help() string      { return "tell <nick> <message> - deliver a message next time <nick> speaks" }
+
+func (tellCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	if len(args) < 2 {
+		ctx.Reply("Usage: ;tell <username> <message>")
+		return nil
+	}
+	targetNick := args[0]
+	theMessage := strings.TrimSpace(strings.Join(args[1:], " "))
+	if targetNick == "" || theMessage == "" {
+		ctx.Reply("Usage: ;tell <username> <message>")
+		return nil
+	}
+	targetAccount := resolveAccount(ctx.Network, targetNick)
+	if err := storeTell(ctx.Network, targetAccount, ctx.Account, theMessage); err != nil {
+		ctx.Reply(fmt.Sprintf("Error storing tell: %v", err))
+		return err
+	}
+	ctx.Reply(fmt.Sprintf("Okay, %s. I'll tell %s next time they speak.", ctx.Nick, targetNick))
+	return nil
+}
+
+// pointsCommand implements ;addpoint/;ap and ;rmpoint/;rp.
+type pointsCommand struct {
+	add bool
+}
+
+func (p pointsCommand) Name() string {
+	if p.add {
+		return "addpoint"
+	}
+	return "rmpoint"
+}
+
+func (p pointsCommand) Aliases() []string {
+	if p.add {
+		return []string{"ap"}
+	}
+	return []string{"rp"}
+}
+
+func (p pointsCommand) Help() string {
+	if p.add {
+		return "addpoint <nick> - give <nick> a point"
+	}
+	return "rmpoint <nick> - take a point from <nick>"
+}
+
+func (p pointsCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	if len(args) == 0 {
+		ctx.Reply(fmt.Sprintf("Usage: ;%s <username>", p.Name()))
+		return nil
+	}
+	target := strings.TrimSpace(args[0])
+	if target == "" {
+		ctx.Reply(fmt.Sprintf("Usage: ;%s <username>", p.Name()))
+		return nil
+	}
+
+	// Resolve to the account up front: it's what the user_points row is
+	// keyed on below, so it needs to be the same identity allowPointGrant
+	// just checked against, or a self-point could slip through under a
+	// second nick spelling of the same account.
+	targetAccount := resolveAccount(ctx.Network, target)
+	if allowed, reason := allowPointGrant(ctx.Account, targetAccount); !allowed {
+		ctx.Reply(fmt.Sprintf("%s: %s", ctx.Nick, reason))
+		return nil
+	}
+
+	if p.add {
+		newVal, err := addPoint(ctx.Network, ctx.Channel, ctx.Account, targetAccount)
+		if err != nil {
+			ctx.Reply(fmt.Sprintf("Database error adding point: %v", err))
+			return err
+		}
+		ctx.Reply(fmt.Sprintf("%s: You now have %d points for %s.", ctx.Nick, newVal, target))
+		return nil
+	}
+
+	newVal, err := removePoint(ctx.Network, ctx.Channel, ctx.Account, targetAccount)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("Database error removing point: %v", err))
+		return err
+	}
+	ctx.Reply(fmt.Sprintf("You now have %d points for %s.", newVal, target))
+	return nil
+}
+
+type badgeCmd struct{}
+
+func (badgeCmd) Name() string      { return "badge" }
+func (badgeCmd) Aliases() []string { return nil }
+func (badgeCmd) Help() string {
+	return `badge [-add -name="x" -date="y"] [-delete -name="x"] - manage your badges`
+}
+
+func (badgeCmd) Handle(ctx *pluginapi.Context, args []string) error {
+	cmd, err := parseBadgeCommand(ctx.Raw)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("%s: %v", ctx.Nick, err))
+		return nil
+	}
+
+	switch cmd.action {
+	case "add":
+		storeDate := parseOrConvertDate(cmd.date)
+		if _, dbErr := db.Exec(`
+			INSERT INTO badges (network, channel, name, date, nick) VALUES (?, ?, ?, ?, ?)
+		`, ctx.Network, ctx.Channel, cmd.name, storeDate, ctx.Nick); dbErr != nil {
+			if strings.Contains(dbErr.Error(), "UNIQUE constraint failed") {
+				ctx.Reply(fmt.Sprintf("%s, you already have a badge named '%s'.", ctx.Nick, cmd.name))
+			} else {
+				ctx.Reply(fmt.Sprintf("Failed to add badge: %v", dbErr))
+				return dbErr
+			}
+		} else {
+			ctx.Reply(fmt.Sprintf("User %s added badge '%s'.", ctx.Nick, cmd.name))
+			PublishNote(ctx.Network, ctx.Channel, ctx.Nick, fmt.Sprintf("%s earned the badge \"%s\".", ctx.Nick, cmd.name))
+		}
+
+	case "delete":
+		res, dbErr := db.Exec(`
+			DELETE FROM badges WHERE network = ? AND channel = ? AND name = ? AND nick = ?
+		`, ctx.Network, ctx.Channel, cmd.name, ctx.Nick)
+		if dbErr != nil {
+			ctx.Reply(fmt.Sprintf("Failed to delete badge: %v", dbErr))
+			return dbErr
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			ctx.Reply(fmt.Sprintf("No badge named '%s' found under your nickname, %s.", cmd.name, ctx.Nick))
+		} else {
+			ctx.Reply(fmt.Sprintf("User %s deleted their badge '%s'.", ctx.Nick, cmd.name))
+		}
+
+	case "show":
+		rows, queryErr := db.Query(`
+			SELECT name, date FROM badges WHERE network = ? AND channel = ? AND nick = ?
+		`, ctx.Network, ctx.Channel, ctx.Nick)
+		if queryErr != nil {
+			ctx.Reply(fmt.Sprintf("Failed to list badges: %v", queryErr))
+			return queryErr
+		}
+		defer rows.Close()
+
+		var badges []string
+		for rows.Next() {
+			var badgeName, storedDate string
+			if err := rows.Scan(&badgeName, &storedDate); err != nil {
+				log.Printf("[ERROR] Read badge row: %v", err)
+				continue
+			}
+			daysOld := daysSince(storedDate)
+			badges = append(badges, fmt.Sprintf("%s (%d days)", badgeName, daysOld))
+		}
+		if len(badges) == 0 {
+			ctx.Reply(fmt.Sprintf("User %s has no badges.", ctx.Nick))
+		} else {
+			ctx.Reply(fmt.Sprintf("User %s's badges: %s", ctx.Nick, strings.Join(badges, ", ")))
+		}
+	}
+	return nil
+}
+
+// helpCommand enumerates every command currently in the registry.
+type helpCommand struct {
+	reg *CommandRegistry
+}
+
+func (helpCommand) Name() string      { return "help" }
+func (helpCommand) Aliases() []string { return nil }
+func (helpCommand) Help() string      { return "help - list available commands" }
+
+func (h helpCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	var lines []string
+	for _, cmd := range h.reg.All() {
+		lines = append(lines, fmt.Sprintf(";%s", cmd.Help()))
+	}
+	ctx.Reply(strings.Join(lines, " | "))
+	return nil
+}
+
+// registerBuiltins wires every first-party command into reg.
+func registerBuiltins(reg *CommandRegistry) {
+	reg.Register(weatherCommand{})
+	reg.Register(askCommand{})
+	reg.Register(huntCommand{word: "bef"})
+	reg.Register(huntCommand{word: "bang"})
+	reg.Register(huntCommand{word: "huntscore"})
+	reg.Register(tellCommand{})
+	reg.Register(pointsCommand{add: true})
+	reg.Register(pointsCommand{add: false})
+	reg.Register(badgeCmd{})
+	reg.Register(helpCommand{reg: reg})
+}
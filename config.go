@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*********************************************************************
+ * Multi-Network Configuration
+ *********************************************************************/
+
+// ChannelConfig describes one channel the bot should join on a network,
+// plus per-channel overrides.
+type ChannelConfig struct {
+	Name string `yaml:"name"`
+	// Commands, if non-nil, enables or disables individual commands in
+	// this channel by name. A command absent from the map uses its
+	// default (enabled).
+	Commands map[string]bool `yaml:"commands,omitempty"`
+	// CooldownSeconds rate-limits how often a single nick may invoke any
+	// command in this channel. Zero means no cooldown.
+	CooldownSeconds int `yaml:"cooldown_seconds,omitempty"`
+}
+
+// NetworkConfig describes one IRC network to connect to.
+type NetworkConfig struct {
+	Name     string          `yaml:"name"`
+	Server   string          `yaml:"server"`
+	Nick     string          `yaml:"nick"`
+	TLS      bool            `yaml:"tls"`
+	TLSCert  string          `yaml:"tls_cert,omitempty"`
+	TLSKey   string          `yaml:"tls_key,omitempty"`
+	TLSCA    string          `yaml:"tls_ca,omitempty"`
+
+	SASLLogin    string `yaml:"sasl_login,omitempty"`
+	SASLPassword string `yaml:"sasl_password,omitempty"`
+	NickServPass string `yaml:"nickserv_password,omitempty"`
+
+	Channels []ChannelConfig `yaml:"channels"`
+}
+
+// Config is the root of the bot's -config YAML file.
+type Config struct {
+	Networks []NetworkConfig `yaml:"networks"`
+}
+
+// LoadConfig reads and parses a multi-network config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %q: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %q: %w", path, err)
+	}
+	if len(cfg.Networks) == 0 {
+		return nil, fmt.Errorf("config %q defines no networks", path)
+	}
+	for i := range cfg.Networks {
+		if cfg.Networks[i].Name == "" {
+			return nil, fmt.Errorf("config %q: network %d has no name", path, i)
+		}
+		if len(cfg.Networks[i].Channels) == 0 {
+			return nil, fmt.Errorf("config %q: network %q joins no channels", path, cfg.Networks[i].Name)
+		}
+	}
+	return &cfg, nil
+}
+
+// legacyNetworkConfigFromEnv builds a single NetworkConfig from the
+// NICKSERV_PASS / NICKNAME / IRC_SERVER / CHANNEL / SASL_* environment
+// variables the bot used before -config existed, so a single-network
+// deployment doesn't need a config file.
+func legacyNetworkConfigFromEnv() NetworkConfig {
+	nick := os.Getenv("NICKNAME")
+	if nick == "" {
+		nick = "jadebot"
+	}
+	server := os.Getenv("IRC_SERVER")
+	if server == "" {
+		server = "irc.snoonet.org:6697"
+	}
+	chanName := os.Getenv("CHANNEL")
+	if chanName == "" {
+		chanName = "#jadebotdev"
+	}
+
+	return NetworkConfig{
+		Name:         "default",
+		Server:       server,
+		Nick:         nick,
+		TLS:          os.Getenv("IRC_NO_TLS") == "",
+		TLSCert:      os.Getenv("IRC_TLS_CERT"),
+		TLSKey:       os.Getenv("IRC_TLS_KEY"),
+		TLSCA:        os.Getenv("IRC_TLS_CA"),
+		SASLLogin:    os.Getenv("SASL_LOGIN"),
+		SASLPassword: os.Getenv("SASL_PASSWORD"),
+		NickServPass: os.Getenv("NICKSERV_PASS"),
+		Channels:     []ChannelConfig{{Name: chanName}},
+	}
+}
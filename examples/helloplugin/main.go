@@ -0,0 +1,45 @@
+// Command helloplugin is a minimal example of a shalerocksbot-go command
+// plugin. Build it with:
+//
+//	go build -buildmode=plugin -o helloplugin.so examples/helloplugin/main.go
+//
+// and point the bot at the directory holding the .so with:
+//
+//	./shalerocksbot-go -plugins /path/to/plugins
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+type helloCommand struct{}
+
+func (helloCommand) Name() string      { return "hello" }
+func (helloCommand) Aliases() []string { return []string{"hi"} }
+func (helloCommand) Help() string      { return "hello - say hi back" }
+
+func (helloCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	target := ctx.Nick
+	if len(args) > 0 {
+		target = strings.Join(args, " ")
+	}
+	ctx.Reply(fmt.Sprintf("Hello, %s!", target))
+	return nil
+}
+
+// Register is the symbol the bot's plugin loader looks up. It must match
+// pluginapi.RegisterFunc.
+func Register(reg pluginapi.Registry, db *sql.DB) {
+	fmt.Println("[helloplugin] registering ;hello")
+	reg.Register(helloCommand{})
+}
+
+// main is never called: plugin.Open ignores it entirely and only looks up
+// the Register symbol above. It's here so `go build ./...` can still
+// compile this directory as an ordinary package; building it for real use
+// is still `go build -buildmode=plugin -o helloplugin.so examples/helloplugin/main.go`.
+func main() {}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*********************************************************************
+ * Context-Aware HTTP Client
+ *
+ * httpClient (see main.go) is fine for a single request, but the
+ * weather and geocoding calls talk to two flaky third-party APIs over
+ * IRC, where a slow response shouldn't hang a command forever. doGet
+ * wraps httpClient with a caller-supplied deadline and a small
+ * exponential-backoff-with-jitter retry loop for transient failures.
+ *********************************************************************/
+
+// cancelEntry is one key's in-flight cancellation signal: closing ch wakes
+// the goroutine waiting on it, and once guards against closing it twice
+// (the timeout firing and the request's own cleanup can both race to do
+// so).
+type cancelEntry struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+func (e *cancelEntry) cancel() {
+	e.once.Do(func() { close(e.ch) })
+}
+
+// requestCanceler hands out a context per key that's cancelled by
+// whichever comes first: the timeout, the caller's own cleanup, or a
+// later Start call for the same key. The last part is the point: a
+// second ;weather from the same nick supersedes (cancels) whatever
+// ;weather that nick already had in flight, rather than letting both
+// race to reply.
+type requestCanceler struct {
+	mu      sync.Mutex
+	entries map[string]*cancelEntry
+}
+
+func newRequestCanceler() *requestCanceler {
+	return &requestCanceler{entries: make(map[string]*cancelEntry)}
+}
+
+// Start cancels any request already in flight for key, then returns a
+// context bounded by parent and timeout for a new one. The caller must
+// call the returned cancel func when its request finishes, so Start can
+// tell a superseding call from one that already completed.
+func (c *requestCanceler) Start(parent context.Context, key string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	entry := &cancelEntry{ch: make(chan struct{})}
+
+	c.mu.Lock()
+	if prev, ok := c.entries[key]; ok {
+		prev.cancel()
+	}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	ctx, cancelCtx := context.WithCancel(parent)
+	timer := time.AfterFunc(timeout, entry.cancel)
+
+	go func() {
+		<-entry.ch
+		cancelCtx()
+	}()
+
+	release := func() {
+		timer.Stop()
+		entry.cancel()
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	return ctx, release
+}
+
+const (
+	httpMaxRetries  = 3
+	httpRetryBase   = 250 * time.Millisecond
+	httpRetryJitter = 150 * time.Millisecond
+)
+
+// doGet issues a GET to reqURL using httpClient, honoring ctx for both
+// cancellation and the overall deadline, and retrying transient
+// failures (network errors and 5xx responses) with exponential backoff
+// plus jitter. It does not retry 4xx responses, since those won't
+// change on their own. Callers are responsible for closing the returned
+// response's body.
+func doGet(ctx context.Context, reqURL string, configure func(*http.Request)) (*http.Response, error) {
+	delay := httpRetryBase
+	var lastErr error
+
+	for attempt := 0; attempt <= httpMaxRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("building request: %w", err)
+		}
+		if configure != nil {
+			configure(req)
+		}
+
+		resp, err := httpClient.Do(req)
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode >= 500:
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server returned status %d", resp.StatusCode)
+		default:
+			return resp, nil
+		}
+
+		if attempt == httpMaxRetries {
+			break
+		}
+		if err := sleepWithJitter(ctx, delay); err != nil {
+			return nil, err
+		}
+		delay *= 2
+	}
+	return nil, fmt.Errorf("after %d attempts: %w", httpMaxRetries+1, lastErr)
+}
+
+// sleepWithJitter waits delay plus a random jitter, returning early with
+// ctx.Err() if ctx is cancelled or its deadline expires first.
+func sleepWithJitter(ctx context.Context, delay time.Duration) error {
+	jitter := time.Duration(rand.Int63n(int64(httpRetryJitter)))
+	timer := time.NewTimer(delay + jitter)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDoGetRetriesThenSucceeds checks doGet's core retry contract: a 502
+// on the first attempt is retried, and a 200 on the next attempt is
+// returned rather than treated as a final failure.
+func TestDoGetRetriesThenSucceeds(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	resp, err := doGet(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatalf("doGet: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("server saw %d attempts, want 2", got)
+	}
+}
+
+// TestDoGetCancelMidFlight checks that doGet gives up promptly when its
+// context is cancelled while a request is in progress, rather than
+// waiting out the retry loop.
+func TestDoGetCancelMidFlight(t *testing.T) {
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := doGet(ctx, srv.URL, nil)
+		done <- err
+	}()
+
+	// Give the request a moment to actually reach the (blocked) handler
+	// before cancelling it.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("doGet returned no error after its context was cancelled mid-flight")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("doGet did not return promptly after cancellation")
+	}
+}
+
+// TestRequestCancelerSupersedes checks the behavior weatherCommand relies
+// on: starting a second request for the same key cancels the first one's
+// context.
+func TestRequestCancelerSupersedes(t *testing.T) {
+	c := newRequestCanceler()
+
+	firstCtx, firstCancel := c.Start(context.Background(), "alice", time.Minute)
+	defer firstCancel()
+
+	select {
+	case <-firstCtx.Done():
+		t.Fatal("first context already done before a second Start call")
+	default:
+	}
+
+	secondCtx, secondCancel := c.Start(context.Background(), "alice", time.Minute)
+	defer secondCancel()
+
+	select {
+	case <-firstCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("starting a second request for the same key did not cancel the first")
+	}
+
+	select {
+	case <-secondCtx.Done():
+		t.Fatal("second context should still be live")
+	default:
+	}
+}
+
+// TestRequestCancelerTimeout checks that a request's context is cancelled
+// once its own timeout elapses, independent of any superseding call.
+func TestRequestCancelerTimeout(t *testing.T) {
+	c := newRequestCanceler()
+
+	ctx, cancel := c.Start(context.Background(), "bob", 20*time.Millisecond)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled after its timeout elapsed")
+	}
+}
+
+// TestRequestCancelerDifferentKeysIndependent checks that two different
+// keys don't interfere with each other.
+func TestRequestCancelerDifferentKeysIndependent(t *testing.T) {
+	c := newRequestCanceler()
+
+	aliceCtx, aliceCancel := c.Start(context.Background(), "alice", time.Minute)
+	defer aliceCancel()
+	_, bobCancel := c.Start(context.Background(), "bob", time.Minute)
+	defer bobCancel()
+
+	select {
+	case <-aliceCtx.Done():
+		t.Fatal("starting a request for a different key cancelled alice's context")
+	default:
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+/*********************************************************************
+ * IRCv3: TLS + SASL
+ *********************************************************************/
+
+// configureTLSAndSASL wires up TLS and SASL on bot from cfg, before
+// Connect is called. TLS is on whenever cfg.TLS is set; SASL is only
+// enabled if PLAIN credentials or a client certificate (EXTERNAL) are
+// present.
+func configureTLSAndSASL(bot *irc.Connection, cfg NetworkConfig) error {
+	if cfg.TLS {
+		tlsConfig := &tls.Config{}
+
+		if cfg.TLSCA != "" {
+			caPEM, err := os.ReadFile(cfg.TLSCA)
+			if err != nil {
+				return fmt.Errorf("reading tls_ca %q: %w", cfg.TLSCA, err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil || pool == nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return fmt.Errorf("no certificates found in tls_ca %q", cfg.TLSCA)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.TLSCert != "" && cfg.TLSKey != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+			if err != nil {
+				return fmt.Errorf("loading client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		bot.UseTLS = true
+		bot.TLSConfig = tlsConfig
+	}
+
+	switch {
+	case bot.TLSConfig != nil && len(bot.TLSConfig.Certificates) > 0 && cfg.SASLPassword == "":
+		// A client cert with no PLAIN password means EXTERNAL.
+		bot.UseSASL = true
+		bot.SASLMech = "EXTERNAL"
+		bot.SASLLogin = cfg.SASLLogin
+	case cfg.SASLLogin != "" && cfg.SASLPassword != "":
+		bot.UseSASL = true
+		bot.SASLMech = "PLAIN"
+		bot.SASLLogin = cfg.SASLLogin
+		bot.SASLPassword = cfg.SASLPassword
+	}
+
+	return nil
+}
+
+// accountFor returns the IRCv3 account name behind e, if the server sent
+// an account-tag, falling back to the (transient) nick otherwise. Points
+// and tells key on this so they survive the sender changing nicks.
+func accountFor(e *irc.Event) string {
+	if e.Tags != nil {
+		if account, ok := e.Tags["account"]; ok && account != "" && account != "*" {
+			return account
+		}
+	}
+	return e.Nick
+}
+
+/*********************************************************************
+ * Nick -> Account Cache
+ *
+ * The account-tag on a PRIVMSG only tells us the *speaker's* account.
+ * Commands like ;tell take a nick argument for someone else, who may
+ * not have spoken recently (or ever) for us to have seen their account
+ * tagged directly. rememberAccount is fed from every tagged PRIVMSG and
+ * from account-notify's ACCOUNT messages, so resolveAccount has a
+ * decent chance of already knowing who a typed nick really is.
+ *********************************************************************/
+
+var (
+	nickAccountsMu sync.RWMutex
+	nickAccounts   = map[string]string{}
+)
+
+func accountCacheKey(network, nick string) string {
+	return strings.ToLower(network) + "|" + strings.ToLower(nick)
+}
+
+// rememberAccount records that nick on network is currently authenticated
+// as account, so a later resolveAccount(network, nick) can find it.
+func rememberAccount(network, nick, account string) {
+	if nick == "" || account == "" {
+		return
+	}
+	nickAccountsMu.Lock()
+	nickAccounts[accountCacheKey(network, nick)] = account
+	nickAccountsMu.Unlock()
+}
+
+// forgetAccount drops a cached nick->account mapping, e.g. when
+// account-notify reports the nick has logged out.
+func forgetAccount(network, nick string) {
+	nickAccountsMu.Lock()
+	delete(nickAccounts, accountCacheKey(network, nick))
+	nickAccountsMu.Unlock()
+}
+
+// resolveAccount returns the account last seen for nick on network, or
+// nick itself if we've never seen one (e.g. the pre-account-tag
+// single-network deployments this bot used to be).
+func resolveAccount(network, nick string) string {
+	nickAccountsMu.RLock()
+	account, ok := nickAccounts[accountCacheKey(network, nick)]
+	nickAccountsMu.RUnlock()
+	if !ok {
+		return nick
+	}
+	return account
+}
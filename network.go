@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+/*********************************************************************
+ * Network: one IRC connection, its channels, and its own animal hunt
+ *********************************************************************/
+
+// Network wraps a single *irc.Connection along with the state that used
+// to be global: which channels it's in, and the currently-spawned animal
+// for its hunt cycle. Each configured network gets its own Network and
+// runs its own goroutines, independent of every other network.
+type Network struct {
+	Name string
+	cfg  NetworkConfig
+	bot  *irc.Connection
+	reg  *CommandRegistry
+
+	animalMu     sync.Mutex
+	activeAnimal currentAnimalState
+}
+
+// networks holds every Network the bot is currently running, keyed by
+// name, so command handlers can resolve ctx.Network back to the
+// connection and channels it needs.
+var (
+	networksMu sync.RWMutex
+	networks   = map[string]*Network{}
+)
+
+func networkByName(name string) *Network {
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+	return networks[name]
+}
+
+// newNetwork builds a Network and registers it in the package-level
+// networks map so handlers can find it by name.
+func newNetwork(cfg NetworkConfig, reg *CommandRegistry) *Network {
+	n := &Network{Name: cfg.Name, cfg: cfg, reg: reg}
+
+	networksMu.Lock()
+	networks[n.Name] = n
+	networksMu.Unlock()
+
+	return n
+}
+
+// connect dials the network, negotiates TLS/SASL/caps, and wires up the
+// callbacks. It does not block; run calls it and then enters the event
+// loop.
+func (n *Network) connect() error {
+	n.bot = irc.IRC(n.cfg.Nick, n.cfg.Nick)
+	n.bot.Server = n.cfg.Server
+	n.bot.Debug = debug
+
+	if err := configureTLSAndSASL(n.bot, n.cfg); err != nil {
+		return fmt.Errorf("configuring TLS/SASL for %s: %w", n.Name, err)
+	}
+
+	// go-ircevent's own negotiateCaps (run inside Connect, below) resets
+	// RequestCaps to nil and only ever adds "sasl" itself before sending
+	// CAP END - setting RequestCaps here is a no-op, so we don't bother.
+	// Everything else we want gets requested with a raw CAP REQ once
+	// we're connected; see the comment below Connect.
+	n.bot.AddCallback("CAP", func(e *irc.Event) {
+		if len(e.Arguments) < 3 {
+			return
+		}
+		switch e.Arguments[1] {
+		case "ACK":
+			for _, capName := range strings.Fields(e.Arguments[2]) {
+				log.Printf("[DEBUG][%s] CAP ACK: %s", n.Name, capName)
+				if capName == "account-tag" {
+					log.Printf("[DEBUG][%s] account-tag acknowledged; PRIVMSGs will carry the sender's resolved account.", n.Name)
+				}
+			}
+		case "NAK":
+			log.Printf("[ERROR][%s] server rejected requested capabilities: %s", n.Name, e.Arguments[2])
+		}
+	})
+
+	n.bot.AddCallback("*", func(e *irc.Event) {
+		log.Printf("[IRC EVENT][%s] Code: %s | Source: %s | Args: %v | Raw: %s",
+			n.Name, e.Code, e.Source, e.Arguments, e.Raw)
+	})
+	n.bot.AddCallback("001", func(e *irc.Event) {
+		log.Printf("[DEBUG][%s] Received RPL_WELCOME: %s", n.Name, e.Raw)
+		if n.bot.UseSASL {
+			log.Printf("[DEBUG][%s] Authenticated via SASL; skipping NickServ IDENTIFY.", n.Name)
+		} else if n.cfg.NickServPass != "" {
+			log.Printf("[DEBUG][%s] Sending NickServ IDENTIFY.", n.Name)
+			n.bot.Privmsgf("NickServ", "IDENTIFY %s", n.cfg.NickServPass)
+		}
+		for _, ch := range n.cfg.Channels {
+			log.Printf("[DEBUG][%s] Joining channel %s now.", n.Name, ch.Name)
+			n.bot.Join(ch.Name)
+		}
+		n.scheduleNextAnimal()
+	})
+	n.bot.AddCallback("ACCOUNT", func(e *irc.Event) {
+		if len(e.Arguments) == 0 {
+			return
+		}
+		if acct := e.Arguments[0]; acct == "*" {
+			forgetAccount(n.Name, e.Nick)
+		} else {
+			rememberAccount(n.Name, e.Nick, acct)
+		}
+	})
+	n.bot.AddCallback("PRIVMSG", n.handlePrivmsg)
+
+	log.Printf("[DEBUG][%s] Attempting to connect to %s...", n.Name, n.cfg.Server)
+	if err := n.bot.Connect(n.cfg.Server); err != nil {
+		return err
+	}
+
+	// Request the non-SASL caps ourselves: by the time Connect returns,
+	// go-ircevent has already sent CAP END and finished its own
+	// negotiation. Servers advertising cap-notify still accept CAP REQ
+	// after registration, which is the only way to get these through
+	// this version of the library.
+	n.bot.SendRaw("CAP REQ :" + strings.Join(postRegistrationCaps, " "))
+	return nil
+}
+
+// postRegistrationCaps are requested with a raw CAP REQ after Connect
+// returns. "account-notify" lets resolveAccount's nick cache pick up
+// account changes without waiting for the nick to speak again.
+var postRegistrationCaps = []string{
+	"server-time",
+	"message-tags",
+	"account-tag",
+	"account-notify",
+	"away-notify",
+	"chghost",
+	"echo-message",
+	"batch",
+}
+
+// run connects (retrying with exponential backoff on failure) and then
+// blocks in the IRC event loop, reconnecting with the same backoff if the
+// connection drops. It never returns.
+func (n *Network) run() {
+	backoff := time.Second
+	const maxBackoff = 5 * time.Minute
+
+	for {
+		if err := n.connect(); err != nil {
+			log.Printf("[ERROR][%s] connect failed: %v (retrying in %s)", n.Name, err, backoff)
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff, maxBackoff)
+			continue
+		}
+
+		backoff = time.Second
+		n.bot.Loop() // blocks until disconnected
+		log.Printf("[ERROR][%s] disconnected, reconnecting in %s", n.Name, backoff)
+		time.Sleep(backoff)
+		backoff = nextBackoff(backoff, maxBackoff)
+	}
+}
+
+func nextBackoff(cur, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		return max
+	}
+	return next
+}
+
+func (n *Network) handlePrivmsg(e *irc.Event) {
+	if len(e.Arguments) == 0 {
+		return
+	}
+	chanName := e.Arguments[0]
+	replyTo := chanName
+	if !strings.HasPrefix(chanName, "#") && !strings.HasPrefix(chanName, "&") {
+		// A query: Arguments[0] is our own nick, so answer the sender.
+		replyTo = e.Nick
+	}
+	account := accountFor(e)
+	rememberAccount(n.Name, e.Nick, account)
+
+	ctx := &pluginapi.Context{
+		Network: n.Name,
+		Channel: chanName,
+		Nick:    e.Nick,
+		Account: account,
+		Reply: func(msg string) {
+			n.bot.Privmsg(replyTo, msg)
+		},
+	}
+
+	deliverTells(n.Name, account, ctx.Reply)
+
+	msg := e.Message()
+	if word, ok := commandWord(msg); ok {
+		ch := n.cfg.channelConfig(chanName)
+		if !commandEnabled(ch, word) {
+			return
+		}
+		if !allowByCooldown(n.Name, ch, e.Nick) {
+			return
+		}
+	}
+	n.reg.Dispatch(ctx, msg)
+}
+
+/*********************************************************************
+ * Animal Hunt Logic
+ *********************************************************************/
+const brown = "\x0305"
+const normal = "\x0f"
+const bold = "\x02"
+const pink = "\x0313"
+
+var possibleAnimals = []struct {
+	name  string
+	sound string
+}{
+	{"duck", brown + "(o)<  ・゜゜・。。・゜゜HONK" + normal},
+	{"pig", brown + "~~(_ _)^" + pink + ":" + brown + " OINK" + normal},
+	{"seal", bold + "(ᵔᴥᵔ) BARK" + normal},
+	{"mouse", brown + "<:3)~ SQEEK" + normal},
+	{"shark", bold + "____/\\_______\\o/___ AHHHH! SHARK" + normal},
+}
+
+func (n *Network) scheduleNextAnimal() {
+	go func() {
+		delay := rand.Intn(3180) + 360 // 360..3539
+		if debug {
+			delay = 8 // 8 seconds when in debug
+		}
+		time.Sleep(time.Duration(delay) * time.Second)
+		n.spawnAnimal()
+	}()
+}
+
+// spawnAnimal picks an animal and announces it in the network's first
+// configured channel. A network with several channels still only gets
+// one animal at a time; splitting the hunt per-channel is left for a
+// future change if anyone asks for it.
+func (n *Network) spawnAnimal() {
+	n.animalMu.Lock()
+	defer n.animalMu.Unlock()
+
+	n.scheduleNextAnimal()
+
+	if len(n.cfg.Channels) == 0 {
+		return
+	}
+
+	idx := rand.Intn(len(possibleAnimals))
+	chosen := possibleAnimals[idx]
+
+	n.activeAnimal = currentAnimalState{
+		animal:  chosen.name,
+		spawned: true,
+		claimed: false,
+	}
+	n.bot.Privmsg(n.cfg.Channels[0].Name, chosen.sound)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plugin"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+/*********************************************************************
+ * 9) Plugins
+ *********************************************************************/
+
+// loadPlugins scans dir for *.so files built with `go build
+// -buildmode=plugin`, opens each one, and calls its exported Register
+// symbol so it can add commands to reg. A plugin that fails to load or
+// whose Register symbol has the wrong type is logged and skipped rather
+// than treated as fatal, so one bad plugin can't take the bot down.
+func loadPlugins(dir string, reg *CommandRegistry, db *sql.DB) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return fmt.Errorf("scanning plugin dir %q: %w", dir, err)
+	}
+
+	for _, path := range matches {
+		if err := loadPlugin(path, reg, db); err != nil {
+			log.Printf("[ERROR] loading plugin %q: %v", path, err)
+			continue
+		}
+		log.Printf("[DEBUG] loaded plugin %q", path)
+	}
+	return nil
+}
+
+func loadPlugin(path string, reg *CommandRegistry, db *sql.DB) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+
+	sym, err := p.Lookup("Register")
+	if err != nil {
+		return err
+	}
+
+	register, ok := sym.(func(pluginapi.Registry, *sql.DB))
+	if !ok {
+		return fmt.Errorf("Register has the wrong signature, want func(pluginapi.Registry, *sql.DB)")
+	}
+
+	register(reg, db)
+	return nil
+}
+
+// pluginsDirFlag is set from the -plugins flag in main(). An empty value
+// (the default) disables plugin loading entirely.
+var pluginsDirFlag string
+
+func pluginsEnabled() bool {
+	return pluginsDirFlag != ""
+}
+
+func maybeLoadPlugins(reg *CommandRegistry) {
+	if !pluginsEnabled() {
+		return
+	}
+	if _, err := os.Stat(pluginsDirFlag); err != nil {
+		log.Printf("[ERROR] -plugins directory %q: %v", pluginsDirFlag, err)
+		return
+	}
+	if err := loadPlugins(pluginsDirFlag, reg, db); err != nil {
+		log.Printf("[ERROR] loadPlugins: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+// fixtureSOPath is built once in TestMain into its own directory (where
+// TestLoadPlugins later adds a junk.so), since Go's plugin package refuses to load two distinct .so
+// files compiled from the same source into one process ("plugin already
+// loaded") - every test that wants a working plugin has to share this one
+// already-loaded copy rather than building or loading its own.
+var fixtureSOPath string
+
+func TestMain(m *testing.M) {
+	wd, err := os.Getwd()
+	if err != nil {
+		os.Exit(1)
+	}
+
+	dir, err := os.MkdirTemp("", "fixtureplugin")
+	if err == nil {
+		soPath := filepath.Join(dir, "fixture.so")
+		cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", soPath, "./testdata/fixtureplugin")
+		cmd.Dir = wd
+		if out, buildErr := cmd.CombinedOutput(); buildErr == nil {
+			fixtureSOPath = soPath
+		} else {
+			os.Stderr.WriteString("building fixture plugin: " + buildErr.Error() + "\n" + string(out) + "\n")
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	os.Exit(m.Run())
+}
+
+func requireFixtureSO(t *testing.T) string {
+	t.Helper()
+	if fixtureSOPath == "" {
+		t.Skip("fixture plugin could not be built (no usable cgo/plugin toolchain?)")
+	}
+	return fixtureSOPath
+}
+
+// TestLoadPlugins covers the directory-scanning path end to end: it
+// points loadPlugins at the fixture's directory (which also contains an
+// unrelated junk.so) and checks that the real plugin registers and runs
+// while the junk one is logged and skipped rather than aborting the scan.
+func TestLoadPlugins(t *testing.T) {
+	soPath := requireFixtureSO(t)
+	dir := filepath.Dir(soPath)
+
+	if err := os.WriteFile(filepath.Join(dir, "junk.so"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatalf("writing junk.so: %v", err)
+	}
+
+	reg := NewCommandRegistry()
+	if err := loadPlugins(dir, reg, nil); err != nil {
+		t.Fatalf("loadPlugins: %v", err)
+	}
+
+	cmd, ok := reg.Lookup("fixture")
+	if !ok {
+		t.Fatalf("expected fixture command to be registered despite junk.so being present")
+	}
+	if _, ok := reg.Lookup("fx"); !ok {
+		t.Errorf("expected fixture's alias %q to be registered too", "fx")
+	}
+
+	var replied string
+	ctx := &pluginapi.Context{Reply: func(msg string) { replied = msg }}
+	if err := cmd.Handle(ctx, nil); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if replied != "fixture ok" {
+		t.Errorf("Handle reply = %q, want %q", replied, "fixture ok")
+	}
+}
+
+func TestLoadPluginMissingFile(t *testing.T) {
+	reg := NewCommandRegistry()
+	err := loadPlugin(filepath.Join(t.TempDir(), "nope.so"), reg, nil)
+	if err == nil {
+		t.Fatalf("loadPlugin on a nonexistent path: want error, got nil")
+	}
+}
+
+func TestLoadPluginsEmptyDir(t *testing.T) {
+	reg := NewCommandRegistry()
+	if err := loadPlugins(t.TempDir(), reg, nil); err != nil {
+		t.Fatalf("loadPlugins on an empty dir: %v", err)
+	}
+	if len(reg.All()) != 0 {
+		t.Fatalf("expected no commands registered from an empty plugin dir")
+	}
+}
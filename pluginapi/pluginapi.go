@@ -0,0 +1,56 @@
+// Package pluginapi defines the stable interface that out-of-tree command
+// plugins build against. It is kept separate from package main so that a
+// plugin compiled with `go build -buildmode=plugin` only needs to import
+// this package (and whatever it needs from the standard library) rather
+// than the bot binary itself.
+package pluginapi
+
+import (
+	"database/sql"
+)
+
+// Context carries everything a Command needs to know about where a
+// message came from, without exposing the bot's internal Network type to
+// plugins. Reply sends a message back to the channel (or nick, for a
+// query) the command was invoked from, on the same network it arrived on.
+type Context struct {
+	Network string // network name, as given in the bot's config
+	Channel string
+	Nick    string // the nick that sent the message
+	Account string // IRCv3 account for Nick, or Nick itself if unauthenticated
+	Raw     string // the full message text, including the leading ';command'
+
+	Reply func(message string)
+}
+
+// Command is implemented by both built-in and plugin-provided commands.
+type Command interface {
+	// Name is the canonical command word, without the leading ';'.
+	Name() string
+	// Aliases lists additional words that should trigger this command.
+	Aliases() []string
+	// Help is a one-line description shown by ;help.
+	Help() string
+	// Handle runs the command for a single incoming PRIVMSG.
+	// args is the message split on whitespace with the command word removed.
+	Handle(ctx *Context, args []string) error
+}
+
+// Registry is the subset of *main.CommandRegistry a plugin is allowed to
+// touch. Plugins register commands through it at load time; they must not
+// assume anything else about how the bot stores or dispatches commands.
+type Registry interface {
+	Register(cmd Command)
+}
+
+// RegisterFunc is the signature every plugin must export as the symbol
+// "Register":
+//
+//	func Register(reg pluginapi.Registry, db *sql.DB)
+//
+// The bot calls it once, right after the plugin's .so is opened, so the
+// plugin can build its commands with a handle to the shared database and
+// hand them to reg.Register. A plugin's commands are shared by every
+// configured network; Context.Reply takes care of answering on whichever
+// network and channel the triggering message came from.
+type RegisterFunc func(reg Registry, db *sql.DB)
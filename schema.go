@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+/*********************************************************************
+ * Database Schema
+ *********************************************************************/
+
+// ensureSchema creates every table the bot needs if they don't already
+// exist, then runs addColumnIfMissing for the network/channel scoping
+// columns added when multi-network support was introduced. Existing
+// badges.db files from single-network installs pick these up in place;
+// old rows simply read back with network = channel = "".
+func ensureSchema(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS badges (
+		    id   INTEGER PRIMARY KEY AUTOINCREMENT,
+		    name TEXT NOT NULL,
+		    date TEXT NOT NULL,
+		    nick TEXT NOT NULL,
+		    UNIQUE(nick, name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS animalhunt (
+		    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+		    nick   TEXT NOT NULL,
+		    animal TEXT NOT NULL,
+		    action TEXT NOT NULL,
+		    date   TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS pending_tells (
+		    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		    targetNick TEXT NOT NULL,
+		    fromNick   TEXT NOT NULL,
+		    message    TEXT NOT NULL,
+		    date       TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_points (
+		    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		    fromNick  TEXT NOT NULL,
+		    toNick    TEXT NOT NULL,
+		    points    INTEGER NOT NULL DEFAULT 0,
+		    UNIQUE(fromNick, toNick)
+		);`,
+		`CREATE TABLE IF NOT EXISTS actor_keys (
+		    nick            TEXT PRIMARY KEY,
+		    private_key_pem TEXT NOT NULL,
+		    public_key_pem  TEXT NOT NULL,
+		    created_at      TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS followers (
+		    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		    nick       TEXT NOT NULL,
+		    actor      TEXT NOT NULL,
+		    inbox      TEXT NOT NULL,
+		    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    UNIQUE(nick, actor)
+		);`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("creating table: %w", err)
+		}
+	}
+
+	migrations := []struct {
+		table, column string
+	}{
+		{"badges", "network"},
+		{"badges", "channel"},
+		{"animalhunt", "network"},
+		{"animalhunt", "channel"},
+		{"pending_tells", "network"},
+		{"pending_tells", "channel"},
+		{"user_points", "network"},
+		{"user_points", "channel"},
+	}
+	for _, m := range migrations {
+		if err := addColumnIfMissing(db, m.table, m.column); err != nil {
+			return fmt.Errorf("migrating %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	if err := rebuildBadgesUniqueConstraint(db); err != nil {
+		return fmt.Errorf("rebuilding badges unique constraint: %w", err)
+	}
+	if err := rebuildUserPointsUniqueConstraint(db); err != nil {
+		return fmt.Errorf("rebuilding user_points unique constraint: %w", err)
+	}
+	return nil
+}
+
+// rebuildBadgesUniqueConstraint widens badges' UNIQUE constraint from
+// (nick, name) to (network, channel, nick, name). The column migration
+// above only adds network/channel as plain columns; it can't touch the
+// UNIQUE constraint SQLite baked into the table at creation time, so the
+// same nick earning the same badge on two different networks or channels
+// would otherwise collide. SQLite has no ALTER TABLE for constraints, so
+// this rebuilds the table under a transaction instead; it's a no-op once
+// the constraint is already in place.
+func rebuildBadgesUniqueConstraint(db *sql.DB) error {
+	migrated, err := tableSQLContains(db, "badges", "UNIQUE(network, channel, nick, name)")
+	if err != nil || migrated {
+		return err
+	}
+
+	return runInTx(db, []string{
+		`ALTER TABLE badges RENAME TO badges_old`,
+		`CREATE TABLE badges (
+		    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		    network TEXT NOT NULL DEFAULT '',
+		    channel TEXT NOT NULL DEFAULT '',
+		    name    TEXT NOT NULL,
+		    date    TEXT NOT NULL,
+		    nick    TEXT NOT NULL,
+		    UNIQUE(network, channel, nick, name)
+		)`,
+		`INSERT INTO badges (id, network, channel, name, date, nick)
+		    SELECT id, network, channel, name, date, nick FROM badges_old`,
+		`DROP TABLE badges_old`,
+	})
+}
+
+// rebuildUserPointsUniqueConstraint is rebuildBadgesUniqueConstraint's
+// twin for user_points: (fromNick, toNick) widens to (network, channel,
+// fromNick, toNick) so the same pair of nicks on two networks/channels
+// get independent point tallies instead of colliding.
+func rebuildUserPointsUniqueConstraint(db *sql.DB) error {
+	migrated, err := tableSQLContains(db, "user_points", "UNIQUE(network, channel, fromNick, toNick)")
+	if err != nil || migrated {
+		return err
+	}
+
+	return runInTx(db, []string{
+		`ALTER TABLE user_points RENAME TO user_points_old`,
+		`CREATE TABLE user_points (
+		    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		    network  TEXT NOT NULL DEFAULT '',
+		    channel  TEXT NOT NULL DEFAULT '',
+		    fromNick TEXT NOT NULL,
+		    toNick   TEXT NOT NULL,
+		    points   INTEGER NOT NULL DEFAULT 0,
+		    UNIQUE(network, channel, fromNick, toNick)
+		)`,
+		`INSERT INTO user_points (id, network, channel, fromNick, toNick, points)
+		    SELECT id, network, channel, fromNick, toNick, points FROM user_points_old`,
+		`DROP TABLE user_points_old`,
+	})
+}
+
+// tableSQLContains reports whether table's CREATE TABLE statement, as
+// SQLite recorded it in sqlite_master, contains substr - used to check
+// whether a constraint-rebuilding migration has already run.
+func tableSQLContains(db *sql.DB, table, substr string) (bool, error) {
+	var createSQL string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(createSQL, substr), nil
+}
+
+// runInTx executes statements in order inside a single transaction,
+// rolling back on the first error.
+func runInTx(db *sql.DB, statements []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a TEXT NOT NULL DEFAULT '' column to table,
+// tolerating the case where a previous run already added it. SQLite has
+// no "ADD COLUMN IF NOT EXISTS", so we just swallow the one error it
+// returns for a duplicate column.
+func addColumnIfMissing(db *sql.DB, table, column string) error {
+	_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s TEXT NOT NULL DEFAULT ''`, table, column))
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return err
+	}
+	return nil
+}
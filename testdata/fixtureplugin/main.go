@@ -0,0 +1,28 @@
+// Command fixtureplugin is a throwaway plugin built by plugin_test.go to
+// exercise loadPlugins/loadPlugin end to end. It is not part of the bot
+// itself and is never built by `go build ./...` (testdata is skipped by
+// the go tool).
+package main
+
+import (
+	"database/sql"
+
+	"github.com/jadedragon942/shalerocksbot-go/pluginapi"
+)
+
+type fixtureCommand struct{}
+
+func (fixtureCommand) Name() string      { return "fixture" }
+func (fixtureCommand) Aliases() []string { return []string{"fx"} }
+func (fixtureCommand) Help() string      { return "fixture - test plugin" }
+
+func (fixtureCommand) Handle(ctx *pluginapi.Context, args []string) error {
+	ctx.Reply("fixture ok")
+	return nil
+}
+
+func Register(reg pluginapi.Registry, db *sql.DB) {
+	reg.Register(fixtureCommand{})
+}
+
+func main() {}
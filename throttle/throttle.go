@@ -0,0 +1,241 @@
+// Package throttle provides the rate-limiting and anti-abuse primitives
+// the bot uses to keep the hunt and points commands from being farmed:
+// a token bucket for "how often", and a bloom filter persisted to disk
+// for "have we already let this exact thing through today".
+package throttle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+/*********************************************************************
+ * Token bucket
+ *********************************************************************/
+
+// Bucket is a simple token bucket: it holds up to capacity tokens,
+// refilling at refillPerSec tokens/second, and Allow consumes one token
+// if any are available. A zero-value refillPerSec makes the bucket a
+// fixed daily-style allowance that never refills on its own; callers
+// that want a rolling cap should use a small refillPerSec instead (e.g.
+// capacity/86400 for "capacity per day").
+type Bucket struct {
+	mu           sync.Mutex
+	tokens       float64
+	capacity     float64
+	refillPerSec float64
+	last         time.Time
+}
+
+// NewBucket creates a bucket starting full, with the given capacity and
+// refill rate in tokens per second.
+func NewBucket(capacity, refillPerSec float64) *Bucket {
+	return &Bucket{
+		tokens:       capacity,
+		capacity:     capacity,
+		refillPerSec: refillPerSec,
+		last:         time.Now(),
+	}
+}
+
+// Allow reports whether a single token is available right now, and
+// consumes it if so.
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+
+	b.tokens += elapsed * b.refillPerSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+/*********************************************************************
+ * Keyed buckets
+ *********************************************************************/
+
+// Keyed hands out one Bucket per key (e.g. a nick, or a network/channel
+// pair), creating it on first use with the given capacity/refill rate.
+// It's the shape the bot actually wants: one daily points allowance per
+// account, not one allowance shared by everybody.
+type Keyed struct {
+	mu           sync.Mutex
+	buckets      map[string]*Bucket
+	capacity     float64
+	refillPerSec float64
+}
+
+func NewKeyed(capacity, refillPerSec float64) *Keyed {
+	return &Keyed{
+		buckets:      make(map[string]*Bucket),
+		capacity:     capacity,
+		refillPerSec: refillPerSec,
+	}
+}
+
+// Allow reports whether key has a token available right now, creating a
+// fresh full bucket for key if this is the first time it's been seen.
+func (k *Keyed) Allow(key string) bool {
+	k.mu.Lock()
+	b, ok := k.buckets[key]
+	if !ok {
+		b = NewBucket(k.capacity, k.refillPerSec)
+		k.buckets[key] = b
+	}
+	k.mu.Unlock()
+
+	return b.Allow()
+}
+
+/*********************************************************************
+ * Bloom filter, persisted to disk
+ *********************************************************************/
+
+// Filter is a bloom filter sized for n expected items at false-positive
+// rate fpr, persisted to a flat file so that anti-abuse state (e.g.
+// "nick already claimed today's milestone") survives a restart.
+type Filter struct {
+	mu   sync.Mutex
+	path string
+	bits []uint64
+	m    uint
+	k    uint
+}
+
+// Load opens the filter persisted at path, creating a new one sized for
+// n/fpr if the file doesn't exist yet.
+func Load(path string, n uint, fpr float64) (*Filter, error) {
+	f := newFilter(path, n, fpr)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var m, k uint64
+	if err := binary.Read(r, binary.LittleEndian, &m); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &k); err != nil {
+		return nil, err
+	}
+	bits := make([]uint64, (m+63)/64)
+	if err := binary.Read(r, binary.LittleEndian, &bits); err != nil {
+		return nil, err
+	}
+
+	f.m, f.k, f.bits = uint(m), uint(k), bits
+	return f, nil
+}
+
+// NewFilter builds a fresh, empty Filter sized for n expected items at
+// false-positive rate fpr, persisted to path on the next Save. Unlike
+// Load, it never reads path - callers that want to discard an existing
+// filter's state (e.g. a daily rotation) can use this instead.
+func NewFilter(path string, n uint, fpr float64) *Filter {
+	return newFilter(path, n, fpr)
+}
+
+func newFilter(path string, n uint, fpr float64) *Filter {
+	if n == 0 {
+		n = 1
+	}
+	m := uint(math.Ceil(-float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	if m == 0 {
+		m = 1
+	}
+	k := uint(math.Round((float64(m) / float64(n)) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return &Filter{
+		path: path,
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (f *Filter) hashes(key string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	sum1 := h1.Sum64()
+
+	h2 := fnv.New64a()
+	h2.Write([]byte(key))
+	h2.Write([]byte{0xff})
+	sum2 := h2.Sum64()
+
+	return sum1, sum2
+}
+
+// Test reports whether key was probably added before.
+func (f *Filter) Test(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h1, h2 := f.hashes(key)
+	for i := uint(0); i < f.k; i++ {
+		idx := (h1 + uint64(i)*h2) % uint64(f.m)
+		if f.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Add marks key as seen.
+func (f *Filter) Add(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h1, h2 := f.hashes(key)
+	for i := uint(0); i < f.k; i++ {
+		idx := (h1 + uint64(i)*h2) % uint64(f.m)
+		f.bits[idx/64] |= 1 << (idx % 64)
+	}
+}
+
+// Save writes the filter to its path, overwriting whatever was there.
+func (f *Filter) Save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := binary.Write(w, binary.LittleEndian, uint64(f.m)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint64(f.k)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, f.bits); err != nil {
+		return err
+	}
+	return w.Flush()
+}
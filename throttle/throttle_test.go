@@ -0,0 +1,54 @@
+package throttle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestFilterFalsePositiveRate sizes a filter for n items at a 1% target
+// rate, adds exactly n of them, then checks that a large disjoint set of
+// keys that were never added comes back positive at roughly that rate -
+// not zero (that would mean Test is broken) and not wildly above it
+// (that would mean the sizing math in newFilter is off).
+func TestFilterFalsePositiveRate(t *testing.T) {
+	const n = 5000
+	const targetFPR = 0.01
+
+	f := NewFilter("", n, targetFPR)
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("member-%d", i))
+	}
+
+	const trials = 20000
+	falsePositives := 0
+	for i := 0; i < trials; i++ {
+		if f.Test(fmt.Sprintf("absent-%d", i)) {
+			falsePositives++
+		}
+	}
+
+	rate := float64(falsePositives) / float64(trials)
+	// Generous bounds: a real bloom filter's observed rate varies with
+	// hash quality and rounding in m/k, so this checks it's in the right
+	// ballpark (not literally exact, not orders of magnitude off).
+	if rate > targetFPR*3 {
+		t.Errorf("false-positive rate = %.4f, want <= ~%.4f (3x target %.4f)", rate, targetFPR*3, targetFPR)
+	}
+	if falsePositives == 0 {
+		t.Errorf("false-positive rate = 0 over %d trials, want roughly %.4f", trials, targetFPR)
+	}
+}
+
+// TestFilterNoFalseNegatives checks the one guarantee a bloom filter
+// must never break: everything actually added always tests positive.
+func TestFilterNoFalseNegatives(t *testing.T) {
+	f := NewFilter("", 1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("member-%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Test(fmt.Sprintf("member-%d", i)) {
+			t.Fatalf("member-%d was added but Test reported it absent", i)
+		}
+	}
+}